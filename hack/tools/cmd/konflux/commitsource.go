@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// CommitSource discovers the merge commits landed on a single branch,
+// abstracting over where that history actually lives: a local git remote, or
+// a forge's REST API. This lets --config point the tool at branches hosted
+// across several different systems instead of one hardcoded git remote.
+type CommitSource interface {
+	// Branch identifies the branch this source reports commits for, used to
+	// label output and metrics.
+	Branch() string
+	// MergeCommits returns this source's merge commits, stopping once
+	// firstCommit is reached when it is set.
+	MergeCommits(ctx context.Context, firstCommit string) ([]commitInfo, error)
+}
+
+// gitCommitSource is a CommitSource backed by a RepoBackend, i.e. a plain git
+// remote.
+type gitCommitSource struct {
+	backend RepoBackend
+	remote  string
+	branch  string
+}
+
+func (s *gitCommitSource) Branch() string { return s.branch }
+
+func (s *gitCommitSource) MergeCommits(ctx context.Context, firstCommit string) ([]commitInfo, error) {
+	return s.backend.MergeCommits(ctx, s.remote, s.branch, firstCommit)
+}
+
+// githubCommitSource is a CommitSource backed by the GitHub REST API.
+type githubCommitSource struct {
+	cache  *httpCache
+	client *http.Client
+
+	host, owner, repo, branch string
+}
+
+func (s *githubCommitSource) Branch() string { return s.branch }
+
+type githubCommit struct {
+	SHA    string `json:"sha"`
+	Commit struct {
+		Committer struct {
+			Date time.Time `json:"date"`
+		} `json:"committer"`
+	} `json:"commit"`
+	Parents []struct {
+		SHA string `json:"sha"`
+	} `json:"parents"`
+}
+
+func (s *githubCommitSource) MergeCommits(ctx context.Context, firstCommit string) ([]commitInfo, error) {
+	var commits []commitInfo
+	for page := 1; ; page++ {
+		uri := fmt.Sprintf("%s/repos/%s/%s/commits?sha=%s&per_page=100&page=%d",
+			s.host, s.owner, s.repo, url.QueryEscape(s.branch), page)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+
+		body, _, err := s.cache.get(s.client, req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch commits page %d for %s/%s: %w", page, s.owner, s.repo, err)
+		}
+
+		var pageCommits []githubCommit
+		if err := json.Unmarshal(body, &pageCommits); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal github commits page %d: %w", page, err)
+		}
+		if len(pageCommits) == 0 {
+			break
+		}
+
+		stop := false
+		for _, c := range pageCommits {
+			if c.SHA == firstCommit {
+				stop = true
+				break
+			}
+			if len(c.Parents) > 1 {
+				commits = append(commits, commitInfo{sha: c.SHA, date: c.Commit.Committer.Date, branch: s.branch})
+			}
+		}
+		if stop || len(pageCommits) < 100 {
+			break
+		}
+	}
+	return commits, nil
+}
+
+// gitlabCommitSource is a CommitSource backed by the GitLab REST API.
+type gitlabCommitSource struct {
+	cache  *httpCache
+	client *http.Client
+
+	host, project, branch string
+}
+
+func (s *gitlabCommitSource) Branch() string { return s.branch }
+
+type gitlabCommit struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	ParentIDs []string  `json:"parent_ids"`
+}
+
+func (s *gitlabCommitSource) MergeCommits(ctx context.Context, firstCommit string) ([]commitInfo, error) {
+	var commits []commitInfo
+	for page := 1; ; page++ {
+		uri := fmt.Sprintf("%s/api/v4/projects/%s/repository/commits?ref_name=%s&per_page=100&page=%d",
+			s.host, url.QueryEscape(s.project), url.QueryEscape(s.branch), page)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+
+		body, _, err := s.cache.get(s.client, req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch commits page %d for project %s: %w", page, s.project, err)
+		}
+
+		var pageCommits []gitlabCommit
+		if err := json.Unmarshal(body, &pageCommits); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal gitlab commits page %d: %w", page, err)
+		}
+		if len(pageCommits) == 0 {
+			break
+		}
+
+		stop := false
+		for _, c := range pageCommits {
+			if c.ID == firstCommit {
+				stop = true
+				break
+			}
+			if len(c.ParentIDs) > 1 {
+				commits = append(commits, commitInfo{sha: c.ID, date: c.CreatedAt, branch: s.branch})
+			}
+		}
+		if stop || len(pageCommits) < 100 {
+			break
+		}
+	}
+	return commits, nil
+}
+
+// gerritCommitSource is a CommitSource backed by Gerrit's JSON change API,
+// which prefixes every response with a `)]}'` line to guard against XSSI.
+type gerritCommitSource struct {
+	cache  *httpCache
+	client *http.Client
+
+	host, project, branch string
+}
+
+func (s *gerritCommitSource) Branch() string { return s.branch }
+
+// gerritMagicPrefix guards Gerrit's JSON responses against XSSI and must be
+// stripped before the body can be unmarshalled.
+const gerritMagicPrefix = ")]}'\n"
+
+// gerritTimeLayout is the format Gerrit reports commit timestamps in; it's
+// always UTC and carries nanosecond precision.
+const gerritTimeLayout = "2006-01-02 15:04:05.000000000"
+
+type gerritChange struct {
+	CurrentRevision string                          `json:"current_revision"`
+	Revisions       map[string]gerritChangeRevision `json:"revisions"`
+}
+
+type gerritChangeRevision struct {
+	Commit struct {
+		Committer struct {
+			Date string `json:"date"`
+		} `json:"committer"`
+		Parents []struct {
+			Commit string `json:"commit"`
+		} `json:"parents"`
+	} `json:"commit"`
+}
+
+func (s *gerritCommitSource) MergeCommits(ctx context.Context, firstCommit string) ([]commitInfo, error) {
+	// Gerrit's search grammar has no predicate for "more than one git
+	// parent"; is:merge isn't real and a real server 400s on it. Merge
+	// commits are filtered client-side below instead, from
+	// len(revision.Commit.Parents).
+	query := fmt.Sprintf("project:%s+branch:%s", s.project, s.branch)
+	var commits []commitInfo
+	start := 0
+	for {
+		uri := fmt.Sprintf("%s/changes/?q=%s&o=CURRENT_COMMIT&o=CURRENT_REVISION&n=100&start=%d",
+			s.host, url.QueryEscape(query), start)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+
+		body, _, err := s.cache.get(s.client, req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch changes for gerrit project %s: %w", s.project, err)
+		}
+		body = bytes.TrimPrefix(body, []byte(gerritMagicPrefix))
+
+		var changes []gerritChange
+		if err := json.Unmarshal(body, &changes); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal gerrit changes: %w", err)
+		}
+		if len(changes) == 0 {
+			break
+		}
+
+		stop := false
+		for _, change := range changes {
+			if change.CurrentRevision == firstCommit {
+				stop = true
+				break
+			}
+			revision, ok := change.Revisions[change.CurrentRevision]
+			if !ok {
+				continue
+			}
+			date, err := time.Parse(gerritTimeLayout, revision.Commit.Committer.Date)
+			if err != nil {
+				return nil, fmt.Errorf("invalid gerrit commit date %q: %w", revision.Commit.Committer.Date, err)
+			}
+			if len(revision.Commit.Parents) > 1 {
+				commits = append(commits, commitInfo{sha: change.CurrentRevision, date: date, branch: s.branch})
+			}
+		}
+		if stop || len(changes) < 100 {
+			break
+		}
+		start += len(changes)
+	}
+	return commits, nil
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// parents1 and parents2 are stand-ins for a single-parent and a two-parent
+// (merge) commit, used to build forge fixtures below.
+var (
+	parents1 = []string{"p0"}
+	parents2 = []string{"p0", "p1"}
+)
+
+func TestGithubCommitSourceMergeCommits(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+
+	page1 := make([]githubCommit, 100)
+	for i := range page1 {
+		page1[i].SHA = fmt.Sprintf("page1-%03d", i)
+		page1[i].Commit.Committer.Date = now
+		parents := parents1
+		if i == 50 || i == 90 {
+			parents = parents2
+		}
+		for _, p := range parents {
+			page1[i].Parents = append(page1[i].Parents, struct {
+				SHA string `json:"sha"`
+			}{SHA: p})
+		}
+	}
+	const firstCommit = "page2-002"
+	page2 := []githubCommit{
+		{SHA: "page2-000", Commit: page1[0].Commit, Parents: page1[90].Parents}, // merge, before the boundary
+		{SHA: "page2-001", Commit: page1[0].Commit, Parents: page1[0].Parents},
+		{SHA: firstCommit, Commit: page1[0].Commit, Parents: page1[90].Parents}, // boundary: never reached as output
+		{SHA: "page2-003", Commit: page1[0].Commit, Parents: page1[90].Parents}, // past the boundary: must not appear
+	}
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		var body any
+		switch page {
+		case 1:
+			body = page1
+		case 2:
+			body = page2
+		default:
+			body = []githubCommit{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(body)
+	}))
+	defer server.Close()
+
+	source := &githubCommitSource{
+		cache:  newHTTPCache(t.TempDir()),
+		client: server.Client(),
+		host:   server.URL,
+		owner:  "openshift",
+		repo:   "hypershift",
+		branch: "main",
+	}
+
+	commits, err := source.MergeCommits(context.Background(), firstCommit)
+	if err != nil {
+		t.Fatalf("MergeCommits returned an error: %v", err)
+	}
+	if requests != 2 {
+		t.Fatalf("expected pagination to fetch exactly 2 pages, got %d requests", requests)
+	}
+
+	var shas []string
+	for _, c := range commits {
+		shas = append(shas, c.sha)
+	}
+	expected := []string{"page1-050", "page1-090", "page2-000"}
+	if fmt.Sprint(shas) != fmt.Sprint(expected) {
+		t.Fatalf("expected merge commits %v, got %v", expected, shas)
+	}
+}
+
+func TestGitlabCommitSourceMergeCommits(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+
+	page1 := make([]gitlabCommit, 100)
+	for i := range page1 {
+		page1[i].ID = fmt.Sprintf("page1-%03d", i)
+		page1[i].CreatedAt = now
+		page1[i].ParentIDs = parents1
+		if i == 50 || i == 90 {
+			page1[i].ParentIDs = parents2
+		}
+	}
+	const firstCommit = "page2-002"
+	page2 := []gitlabCommit{
+		{ID: "page2-000", CreatedAt: now, ParentIDs: parents2}, // merge, before the boundary
+		{ID: "page2-001", CreatedAt: now, ParentIDs: parents1},
+		{ID: firstCommit, CreatedAt: now, ParentIDs: parents2}, // boundary: never reached as output
+		{ID: "page2-003", CreatedAt: now, ParentIDs: parents2}, // past the boundary: must not appear
+	}
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		var body any
+		switch page {
+		case 1:
+			body = page1
+		case 2:
+			body = page2
+		default:
+			body = []gitlabCommit{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(body)
+	}))
+	defer server.Close()
+
+	source := &gitlabCommitSource{
+		cache:   newHTTPCache(t.TempDir()),
+		client:  server.Client(),
+		host:    server.URL,
+		project: "openshift/hypershift",
+		branch:  "main",
+	}
+
+	commits, err := source.MergeCommits(context.Background(), firstCommit)
+	if err != nil {
+		t.Fatalf("MergeCommits returned an error: %v", err)
+	}
+	if requests != 2 {
+		t.Fatalf("expected pagination to fetch exactly 2 pages, got %d requests", requests)
+	}
+
+	var shas []string
+	for _, c := range commits {
+		shas = append(shas, c.sha)
+	}
+	expected := []string{"page1-050", "page1-090", "page2-000"}
+	if fmt.Sprint(shas) != fmt.Sprint(expected) {
+		t.Fatalf("expected merge commits %v, got %v", expected, shas)
+	}
+}
+
+// newGerritRevision builds a gerritChangeRevision with the given parent
+// count, dated at t formatted the way a real Gerrit server reports it.
+func newGerritRevision(t time.Time, parentCount int) gerritChangeRevision {
+	var rev gerritChangeRevision
+	rev.Commit.Committer.Date = t.Format(gerritTimeLayout)
+	for i := 0; i < parentCount; i++ {
+		rev.Commit.Parents = append(rev.Commit.Parents, struct {
+			Commit string `json:"commit"`
+		}{Commit: fmt.Sprintf("parent-%d", i)})
+	}
+	return rev
+}
+
+func TestGerritCommitSourceMergeCommits(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+
+	page1 := make([]gerritChange, 100)
+	for i := range page1 {
+		sha := fmt.Sprintf("page1-%03d", i)
+		parents := 1
+		if i == 50 || i == 90 {
+			parents = 2
+		}
+		page1[i] = gerritChange{
+			CurrentRevision: sha,
+			Revisions:       map[string]gerritChangeRevision{sha: newGerritRevision(now, parents)},
+		}
+	}
+	const firstCommit = "page2-002"
+	page2 := []gerritChange{
+		{CurrentRevision: "page2-000", Revisions: map[string]gerritChangeRevision{"page2-000": newGerritRevision(now, 2)}},
+		{CurrentRevision: "page2-001", Revisions: map[string]gerritChangeRevision{"page2-001": newGerritRevision(now, 1)}},
+		{CurrentRevision: firstCommit, Revisions: map[string]gerritChangeRevision{firstCommit: newGerritRevision(now, 2)}},
+		{CurrentRevision: "page2-003", Revisions: map[string]gerritChangeRevision{"page2-003": newGerritRevision(now, 2)}},
+	}
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		start := r.URL.Query().Get("start")
+		var body []gerritChange
+		switch start {
+		case "0":
+			body = page1
+		case "100":
+			body = page2
+		default:
+			body = []gerritChange{}
+		}
+		raw, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("failed to marshal fixture: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		// Gerrit prefixes every JSON response with this XSSI guard; the
+		// client must strip it before unmarshalling.
+		_, _ = w.Write([]byte(gerritMagicPrefix))
+		_, _ = w.Write(raw)
+	}))
+	defer server.Close()
+
+	source := &gerritCommitSource{
+		cache:   newHTTPCache(t.TempDir()),
+		client:  server.Client(),
+		host:    server.URL,
+		project: "openshift/hypershift",
+		branch:  "main",
+	}
+
+	commits, err := source.MergeCommits(context.Background(), firstCommit)
+	if err != nil {
+		t.Fatalf("MergeCommits returned an error: %v", err)
+	}
+	if requests != 2 {
+		t.Fatalf("expected pagination to fetch exactly 2 pages, got %d requests", requests)
+	}
+
+	var shas []string
+	for _, c := range commits {
+		shas = append(shas, c.sha)
+	}
+	expected := []string{"page1-050", "page1-090", "page2-000"}
+	if fmt.Sprint(shas) != fmt.Sprint(expected) {
+		t.Fatalf("expected merge commits %v, got %v", expected, shas)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"sigs.k8s.io/yaml"
+)
+
+// defaultBranches mirrors the branch list this tool used to hardcode before
+// --config existed, and backs the configuration used when no --config is
+// given.
+var defaultBranches = []string{
+	"main",
+	"release-4.18",
+	"release-4.17",
+	"release-4.16",
+	"release-4.15",
+	"release-4.14",
+	"release-4.13",
+	"main-0.1.16-rehearsal-hotfix",
+}
+
+// Config describes the set of CommitSources to inspect, replacing the
+// previously hardcoded branch list so new forges and branches can be added
+// without a rebuild.
+type Config struct {
+	Sources []SourceConfig `json:"sources"`
+
+	// Notify configures the notify subsystem. Notifications are disabled if
+	// unset or if it has no sinks configured.
+	Notify *NotifyConfig `json:"notify,omitempty"`
+}
+
+// SourceConfig configures a single CommitSource. Type selects which of the
+// remaining fields are required: "git" (the default) needs Remote, "github"
+// and "gitlab" need Owner and Repo, and "gerrit" needs Repo. Host is
+// optional for "github" and "gitlab", defaulting to the public instance of
+// each, and required for "gerrit".
+type SourceConfig struct {
+	Branch string `json:"branch"`
+	Type   string `json:"type,omitempty"`
+
+	// Remote configures a "git" source; it must already be fetchable from
+	// the shared git cache (see --repo-url).
+	Remote string `json:"remote,omitempty"`
+
+	// Host, Owner and Repo configure "github", "gitlab" and "gerrit" sources.
+	Host  string `json:"host,omitempty"`
+	Owner string `json:"owner,omitempty"`
+	Repo  string `json:"repo,omitempty"`
+}
+
+func loadConfig(path string) (*Config, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(raw, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// defaultConfig reproduces the tool's pre-"CommitSource" behavior: every
+// branch in defaultBranches, fetched over git from remote.
+func defaultConfig(remote string) *Config {
+	cfg := &Config{}
+	for _, branch := range defaultBranches {
+		cfg.Sources = append(cfg.Sources, SourceConfig{Branch: branch, Type: "git", Remote: remote})
+	}
+	return cfg
+}
+
+func (c *Config) commitSources(backend RepoBackend, cache *httpCache) ([]CommitSource, error) {
+	var sources []CommitSource
+	for _, s := range c.Sources {
+		switch s.Type {
+		case "", "git":
+			remote := s.Remote
+			if remote == "" {
+				return nil, fmt.Errorf("branch %s: git source requires remote", s.Branch)
+			}
+			sources = append(sources, &gitCommitSource{backend: backend, remote: remote, branch: s.Branch})
+		case "github":
+			sources = append(sources, &githubCommitSource{
+				cache:  cache,
+				client: http.DefaultClient,
+				host:   orDefault(s.Host, "https://api.github.com"),
+				owner:  s.Owner,
+				repo:   s.Repo,
+				branch: s.Branch,
+			})
+		case "gitlab":
+			sources = append(sources, &gitlabCommitSource{
+				cache:   cache,
+				client:  http.DefaultClient,
+				host:    orDefault(s.Host, "https://gitlab.com"),
+				project: fmt.Sprintf("%s/%s", s.Owner, s.Repo),
+				branch:  s.Branch,
+			})
+		case "gerrit":
+			if s.Host == "" {
+				return nil, fmt.Errorf("branch %s: gerrit source requires host", s.Branch)
+			}
+			sources = append(sources, &gerritCommitSource{
+				cache:   cache,
+				client:  http.DefaultClient,
+				host:    s.Host,
+				project: s.Repo,
+				branch:  s.Branch,
+			})
+		default:
+			return nil, fmt.Errorf("branch %s: unknown source type %q", s.Branch, s.Type)
+		}
+	}
+	return sources, nil
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := writeConfig(t, `
+sources:
+- branch: main
+  type: github
+  owner: openshift
+  repo: hypershift
+notify:
+  sinks:
+  - url: https://example.com/hook
+`)
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatalf("loadConfig returned an error: %v", err)
+	}
+	if len(cfg.Sources) != 1 || cfg.Sources[0].Branch != "main" || cfg.Sources[0].Type != "github" {
+		t.Fatalf("unexpected sources: %+v", cfg.Sources)
+	}
+	if cfg.Notify == nil || len(cfg.Notify.Sinks) != 1 || cfg.Notify.Sinks[0].URL != "https://example.com/hook" {
+		t.Fatalf("unexpected notify config: %+v", cfg.Notify)
+	}
+}
+
+func TestLoadConfigMissingFile(t *testing.T) {
+	if _, err := loadConfig(filepath.Join(t.TempDir(), "does-not-exist.yaml")); err == nil {
+		t.Fatal("expected an error for a missing config file, got nil")
+	}
+}
+
+func TestLoadConfigInvalidYAML(t *testing.T) {
+	path := writeConfig(t, "sources: [this is not valid")
+	if _, err := loadConfig(path); err == nil {
+		t.Fatal("expected an error for invalid yaml, got nil")
+	}
+}
+
+func TestConfigCommitSources(t *testing.T) {
+	cache := newHTTPCache(t.TempDir())
+
+	cases := []struct {
+		name    string
+		cfg     Config
+		wantErr string
+		check   func(t *testing.T, sources []CommitSource)
+	}{
+		{
+			name: "default type is git",
+			cfg:  Config{Sources: []SourceConfig{{Branch: "main", Remote: "origin"}}},
+			check: func(t *testing.T, sources []CommitSource) {
+				if _, ok := sources[0].(*gitCommitSource); !ok {
+					t.Fatalf("expected a gitCommitSource, got %T", sources[0])
+				}
+			},
+		},
+		{
+			name:    "git source without remote is rejected",
+			cfg:     Config{Sources: []SourceConfig{{Branch: "main", Type: "git"}}},
+			wantErr: "git source requires remote",
+		},
+		{
+			name: "github source defaults host",
+			cfg:  Config{Sources: []SourceConfig{{Branch: "main", Type: "github", Owner: "openshift", Repo: "hypershift"}}},
+			check: func(t *testing.T, sources []CommitSource) {
+				s, ok := sources[0].(*githubCommitSource)
+				if !ok {
+					t.Fatalf("expected a githubCommitSource, got %T", sources[0])
+				}
+				if s.host != "https://api.github.com" {
+					t.Errorf("expected default github host, got %s", s.host)
+				}
+			},
+		},
+		{
+			name: "gitlab source combines owner and repo into a project path",
+			cfg:  Config{Sources: []SourceConfig{{Branch: "main", Type: "gitlab", Owner: "openshift", Repo: "hypershift", Host: "https://gitlab.example.com"}}},
+			check: func(t *testing.T, sources []CommitSource) {
+				s, ok := sources[0].(*gitlabCommitSource)
+				if !ok {
+					t.Fatalf("expected a gitlabCommitSource, got %T", sources[0])
+				}
+				if s.project != "openshift/hypershift" {
+					t.Errorf("expected project openshift/hypershift, got %s", s.project)
+				}
+			},
+		},
+		{
+			name:    "gerrit source without host is rejected",
+			cfg:     Config{Sources: []SourceConfig{{Branch: "main", Type: "gerrit", Repo: "hypershift"}}},
+			wantErr: "gerrit source requires host",
+		},
+		{
+			name: "gerrit source",
+			cfg:  Config{Sources: []SourceConfig{{Branch: "main", Type: "gerrit", Repo: "hypershift", Host: "https://gerrit.example.com"}}},
+			check: func(t *testing.T, sources []CommitSource) {
+				if _, ok := sources[0].(*gerritCommitSource); !ok {
+					t.Fatalf("expected a gerritCommitSource, got %T", sources[0])
+				}
+			},
+		},
+		{
+			name:    "unknown source type is rejected",
+			cfg:     Config{Sources: []SourceConfig{{Branch: "main", Type: "svn"}}},
+			wantErr: `unknown source type "svn"`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			sources, err := tc.cfg.commitSources(nil, cache)
+			if tc.wantErr != "" {
+				if err == nil {
+					t.Fatalf("expected an error containing %q, got nil", tc.wantErr)
+				}
+				if !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected error to contain %q, got %q", tc.wantErr, err.Error())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("commitSources returned an error: %v", err)
+			}
+			if tc.check != nil {
+				tc.check(t, sources)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+// RepoBackend discovers merge commits reachable from a branch, abstracting
+// over how the underlying repository data is obtained so tests can inject a
+// fake without touching the filesystem or network.
+type RepoBackend interface {
+	// MergeCommits returns the merge commits on remote/branch, stopping once
+	// firstCommit is reached when it is set.
+	MergeCommits(ctx context.Context, remote, branch, firstCommit string) ([]commitInfo, error)
+}
+
+// shallowFetchDepth bounds how much history we pull down when firstCommit
+// gives us a boundary to stop at; without it a long-lived repository's full
+// history would dwarf the merge commits we actually care about.
+const shallowFetchDepth = 5000
+
+// goGitBackend is a RepoBackend backed by a bare repository cached on disk,
+// so repeated runs only need to fetch what changed since the last one.
+type goGitBackend struct {
+	// cacheDir holds the bare repository used as our on-disk cache.
+	cacheDir string
+	// repoURL is registered as the remote when the cache has no checkout of
+	// its own to read a remote configuration from. May be empty, in which
+	// case the cache is expected to already have the remote configured.
+	repoURL string
+}
+
+func newGoGitBackend(cacheDir, repoURL string) *goGitBackend {
+	return &goGitBackend{cacheDir: cacheDir, repoURL: repoURL}
+}
+
+func (b *goGitBackend) repo(remote string) (*git.Repository, error) {
+	if err := os.MkdirAll(b.cacheDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create git cache dir %s: %w", b.cacheDir, err)
+	}
+	repo, err := git.PlainOpen(b.cacheDir)
+	if err != nil {
+		if err != git.ErrRepositoryNotExists {
+			return nil, fmt.Errorf("failed to open cached repo at %s: %w", b.cacheDir, err)
+		}
+		repo, err = git.PlainInit(b.cacheDir, true)
+		if err != nil {
+			return nil, fmt.Errorf("failed to init cached repo at %s: %w", b.cacheDir, err)
+		}
+	}
+
+	if b.repoURL != "" {
+		if _, err := repo.Remote(remote); err == git.ErrRemoteNotFound {
+			if _, err := repo.CreateRemote(&config.RemoteConfig{
+				Name: remote,
+				URLs: []string{b.repoURL},
+			}); err != nil {
+				return nil, fmt.Errorf("failed to create remote %s: %w", remote, err)
+			}
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to look up remote %s: %w", remote, err)
+		}
+	}
+	return repo, nil
+}
+
+func (b *goGitBackend) MergeCommits(ctx context.Context, remote, branch, firstCommit string) ([]commitInfo, error) {
+	repo, err := b.repo(remote)
+	if err != nil {
+		return nil, err
+	}
+
+	depth := 0
+	if firstCommit != "" {
+		depth = shallowFetchDepth
+	}
+	refSpec := config.RefSpec(fmt.Sprintf("+refs/heads/%s:refs/remotes/%s/%s", branch, remote, branch))
+	if err := repo.FetchContext(ctx, &git.FetchOptions{
+		RemoteName: remote,
+		RefSpecs:   []config.RefSpec{refSpec},
+		Depth:      depth,
+		Tags:       git.NoTags,
+	}); err != nil && err != git.NoErrAlreadyUpToDate {
+		return nil, fmt.Errorf("failed to fetch %s from remote %s: %w", branch, remote, err)
+	}
+
+	ref, err := repo.Reference(plumbing.NewRemoteReferenceName(remote, branch), true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s/%s: %w", remote, branch, err)
+	}
+
+	commitIter, err := repo.Log(&git.LogOptions{From: ref.Hash()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk log for %s/%s: %w", remote, branch, err)
+	}
+
+	var commits []commitInfo
+	foundBoundary := firstCommit == ""
+	err = commitIter.ForEach(func(c *object.Commit) error {
+		if c.Hash.String() == firstCommit {
+			foundBoundary = true
+			return storer.ErrStop
+		}
+		if c.NumParents() > 1 {
+			commits = append(commits, commitInfo{
+				sha:    c.Hash.String(),
+				date:   c.Committer.When,
+				branch: branch,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect merge commits for %s/%s: %w", remote, branch, err)
+	}
+	if !foundBoundary {
+		// We walked every commit the shallow fetch gave us without ever
+		// reaching firstCommit: either the boundary lies outside our
+		// depth-bounded fetch or it isn't reachable from this branch at all,
+		// and in both cases the result would not be bounded by it. Fail
+		// loudly instead of returning that result.
+		return nil, fmt.Errorf("walked all commits reachable from %s/%s within the shallow fetch depth (%d) without finding first-commit boundary %s; the fetch is too shallow or the boundary is not on this branch", remote, branch, shallowFetchDepth, firstCommit)
+	}
+	return commits, nil
+}
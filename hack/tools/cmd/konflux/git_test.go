@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// newSyntheticRemote lays down a small on-disk repository with a linear
+// history containing one synthetic merge commit (a commit with two parents,
+// built via CommitOptions.Parents rather than an actual merge), so tests can
+// point goGitBackend at a real repository without a network dependency.
+func newSyntheticRemote(t *testing.T) (repoURL, mergeSHA string) {
+	t.Helper()
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatalf("failed to init synthetic remote: %v", err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("failed to get worktree: %v", err)
+	}
+
+	sig := &object.Signature{Name: "test", Email: "test@example.com", When: time.Unix(0, 0)}
+	commit := func(name string, parents []plumbing.Hash) plumbing.Hash {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		if _, err := wt.Add(name); err != nil {
+			t.Fatalf("failed to stage %s: %v", name, err)
+		}
+		hash, err := wt.Commit("add "+name, &git.CommitOptions{Author: sig, Committer: sig, Parents: parents})
+		if err != nil {
+			t.Fatalf("failed to commit %s: %v", name, err)
+		}
+		return hash
+	}
+
+	root := commit("root.txt", nil)
+	side := commit("side.txt", nil)
+	merge := commit("merge.txt", []plumbing.Hash{root, side})
+	commit("tip.txt", nil)
+
+	return dir, merge.String()
+}
+
+func TestGoGitBackendMergeCommits(t *testing.T) {
+	repoURL, mergeSHA := newSyntheticRemote(t)
+	backend := newGoGitBackend(t.TempDir(), repoURL)
+
+	commits, err := backend.MergeCommits(context.Background(), "origin", "master", "")
+	if err != nil {
+		t.Fatalf("MergeCommits returned an error: %v", err)
+	}
+	if len(commits) != 1 {
+		t.Fatalf("expected exactly one merge commit, got %d: %+v", len(commits), commits)
+	}
+	if commits[0].sha != mergeSHA {
+		t.Errorf("expected merge commit %s, got %s", mergeSHA, commits[0].sha)
+	}
+}
+
+func TestGoGitBackendMergeCommitsMissingBoundary(t *testing.T) {
+	repoURL, _ := newSyntheticRemote(t)
+	backend := newGoGitBackend(t.TempDir(), repoURL)
+
+	_, err := backend.MergeCommits(context.Background(), "origin", "master", "0000000000000000000000000000000000000000")
+	if err == nil {
+		t.Fatal("expected an error when first-commit is never reached within the shallow fetch, got nil")
+	}
+	if !strings.Contains(err.Error(), "first-commit boundary") {
+		t.Errorf("expected error to call out the missing first-commit boundary, got: %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// httpCache is a small on-disk HTTP response cache keyed by request URL and
+// revalidated with ETag/If-None-Match, so repeated runs against rate-limited
+// forge APIs only ever pay for what actually changed.
+type httpCache struct {
+	dir string
+}
+
+func newHTTPCache(dir string) *httpCache {
+	return &httpCache{dir: dir}
+}
+
+// cacheEntry is the sidecar persisted alongside a cached response, recording
+// the validator(s) needed to revalidate it.
+type cacheEntry struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+	Body         []byte `json:"body"`
+}
+
+func (c *httpCache) path(uri string) string {
+	sum := sha256.Sum256([]byte(uri))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// get issues req, transparently revalidating against any cached response for
+// the same URL and falling back to the cached body on a 304 (returned with
+// notModified set). req is sent as-is on a cache miss; callers must not set
+// validation headers themselves.
+func (c *httpCache) get(client *http.Client, req *http.Request) (body []byte, notModified bool, err error) {
+	path := c.path(req.URL.String())
+
+	var cached *cacheEntry
+	if raw, readErr := os.ReadFile(path); readErr == nil {
+		var entry cacheEntry
+		if err := json.Unmarshal(raw, &entry); err != nil {
+			return nil, false, fmt.Errorf("failed to unmarshal cache entry %s: %w", path, err)
+		}
+		cached = &entry
+		if entry.ETag != "" {
+			req.Header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	} else if !os.IsNotExist(readErr) {
+		return nil, false, fmt.Errorf("failed to read cache entry %s: %w", path, readErr)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to send request to %s: %w", req.URL, err)
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			log.Printf("failed to close response body: %v", closeErr)
+		}
+	}()
+
+	if resp.StatusCode == http.StatusNotModified {
+		if cached == nil {
+			return nil, false, fmt.Errorf("got 304 Not Modified for %s with no cached response", req.URL)
+		}
+		return cached.Body, true, nil
+	}
+
+	body, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read response body from %s: %w", req.URL, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("failed to fetch %s: %s: %s", req.URL, resp.Status, string(body))
+	}
+
+	if err := c.put(path, cacheEntry{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		Body:         body,
+	}); err != nil {
+		return nil, false, err
+	}
+	return body, false, nil
+}
+
+// load reads a cached response without making a network request, for
+// callers that have already established (e.g. via get) that it's safe to
+// trust what's on disk.
+func (c *httpCache) load(uri string) (body []byte, ok bool, err error) {
+	path := c.path(uri)
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("failed to read cache entry %s: %w", path, err)
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(raw, &entry); err != nil {
+		return nil, false, fmt.Errorf("failed to unmarshal cache entry %s: %w", path, err)
+	}
+	return entry.Body, true, nil
+}
+
+func (c *httpCache) put(path string, entry cacheEntry) error {
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create http cache dir %s: %w", c.dir, err)
+	}
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache entry for %s: %w", path, err)
+	}
+	if err := os.WriteFile(path, raw, 0644); err != nil {
+		return fmt.Errorf("failed to write cache entry %s: %w", path, err)
+	}
+	return nil
+}
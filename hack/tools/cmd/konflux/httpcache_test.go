@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPCacheRevalidatesWithETag(t *testing.T) {
+	const etag = `"v1"`
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+
+	cache := newHTTPCache(t.TempDir())
+	client := server.Client()
+
+	firstReq, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatalf("failed to build request: %v", err)
+	}
+	body, notModified, err := cache.get(client, firstReq)
+	if err != nil {
+		t.Fatalf("first get returned an error: %v", err)
+	}
+	if notModified {
+		t.Fatal("expected the first get, with nothing cached yet, to be a cache miss")
+	}
+	if string(body) != "hello" {
+		t.Fatalf("expected body %q, got %q", "hello", body)
+	}
+
+	secondReq, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatalf("failed to build request: %v", err)
+	}
+	body, notModified, err = cache.get(client, secondReq)
+	if err != nil {
+		t.Fatalf("second get returned an error: %v", err)
+	}
+	if !notModified {
+		t.Fatal("expected the second get to revalidate as not modified")
+	}
+	if string(body) != "hello" {
+		t.Fatalf("expected cached body %q on a 304, got %q", "hello", body)
+	}
+	if requests != 2 {
+		t.Fatalf("expected exactly 2 requests to reach the server, got %d", requests)
+	}
+
+	loaded, ok, err := cache.load(server.URL)
+	if err != nil {
+		t.Fatalf("load returned an error: %v", err)
+	}
+	if !ok {
+		t.Fatal("expected load to find the cached response without hitting the network")
+	}
+	if string(loaded) != "hello" {
+		t.Fatalf("expected load to return %q, got %q", "hello", loaded)
+	}
+	if requests != 2 {
+		t.Fatalf("expected load not to make a network request, still at 2, got %d", requests)
+	}
+}
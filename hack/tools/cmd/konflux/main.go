@@ -1,22 +1,18 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"net/url"
 	"os"
-	"os/exec"
 	"os/signal"
 	"path/filepath"
 	"sort"
 	"strconv"
-	"strings"
 	"syscall"
 	"time"
 
@@ -30,23 +26,35 @@ type options struct {
 
 	firstCommit string
 	remote      string
+	repoURL     string
+	configPath  string
 
 	outputDir string
+
+	watch        bool
+	pollInterval time.Duration
+	httpAddr     string
 }
 
 func defaultOptions() *options {
 	return &options{
-		quayHost: "https://quay.io",
-		remote:   "origin",
+		quayHost:     "https://quay.io",
+		remote:       "origin",
+		pollInterval: 5 * time.Minute,
 	}
 }
 
 func bindOptions(opts *options, flags *pflag.FlagSet) {
 	flags.StringVar(&opts.firstCommit, "first-commit", opts.firstCommit, "The oldest commit for which we search for an image tag.")
 	flags.StringVar(&opts.remote, "remote", opts.remote, "The name of the remote from which branches are fetched.")
+	flags.StringVar(&opts.repoURL, "repo-url", opts.repoURL, "Git URL to fetch commits from. If unset, the git cache under --output-dir must already have --remote configured.")
+	flags.StringVar(&opts.configPath, "config", opts.configPath, "Path to a YAML file configuring the branches and CommitSources to inspect. If unset, a default git-only configuration covering the usual HyperShift branches is used.")
 	flags.StringVar(&opts.quayHost, "quay-host", opts.quayHost, "Host for the Quay instance to query.")
 	flags.StringVar(&opts.quayToken, "quay-token", opts.quayToken, "Bearer token to authenticate with the Quay API.")
 	flags.StringVar(&opts.outputDir, "output-dir", opts.outputDir, "Directory to use for caching data and outputting analysis.")
+	flags.BoolVar(&opts.watch, "watch", opts.watch, "Run continuously, re-fetching tags and commits every --poll-interval instead of exiting after one pass.")
+	flags.DurationVar(&opts.pollInterval, "poll-interval", opts.pollInterval, "How often to re-fetch tags and commits when --watch is set.")
+	flags.StringVar(&opts.httpAddr, "http-addr", opts.httpAddr, "Address to serve /summary.json, /healthz and /metrics on when --watch is set. Disabled if empty.")
 }
 
 func (o *options) Validate() error {
@@ -56,6 +64,9 @@ func (o *options) Validate() error {
 	if o.outputDir == "" {
 		return errors.New("--output-dir is required")
 	}
+	if o.watch && o.pollInterval <= 0 {
+		return errors.New("--poll-interval must be positive when --watch is set")
+	}
 	return nil
 }
 
@@ -71,21 +82,82 @@ func main() {
 		log.Fatal(err)
 	}
 
-	tags, err := publishedTags(ctx, opts.quayHost, opts.quayToken, opts.outputDir)
-	if err != nil {
-		log.Fatal(err)
+	cfg := defaultConfig(opts.remote)
+	if opts.configPath != "" {
+		loaded, err := loadConfig(opts.configPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		cfg = loaded
 	}
 
-	commits, err := mergeCommits(ctx, opts.firstCommit, opts.remote)
+	backend := newGoGitBackend(filepath.Join(opts.outputDir, "git"), opts.repoURL)
+	forgeCache := newHTTPCache(filepath.Join(opts.outputDir, "http"))
+	tagsCache := newHTTPCache(filepath.Join(opts.outputDir, "tags"))
+	sources, err := cfg.commitSources(backend, forgeCache)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	if err := summarize(tags, commits, opts.outputDir); err != nil {
+	if opts.watch {
+		if err := watch(ctx, opts, cfg, sources, tagsCache); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if _, err := runOnce(ctx, opts, cfg, sources, tagsCache); err != nil {
 		log.Fatal(err)
 	}
 }
 
+// runOnce fetches tags and commits, writes the resulting summary.json,
+// notifies cfg.Notify's sinks about whatever changed since the previous run,
+// and returns the summaries so a caller serving them over HTTP doesn't need
+// to read the file back.
+func runOnce(ctx context.Context, opts *options, cfg *Config, sources []CommitSource, tagsCache *httpCache) ([]summary, error) {
+	tags, err := publishedTags(ctx, opts.quayHost, opts.quayToken, tagsCache)
+	if err != nil {
+		return nil, err
+	}
+
+	commits, err := mergeCommits(ctx, sources, opts.firstCommit)
+	if err != nil {
+		return nil, err
+	}
+
+	previous, err := loadSummary(opts.outputDir)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries, err := summarize(tags, commits, opts.outputDir)
+	if err != nil {
+		return nil, err
+	}
+
+	notify(ctx, opts.outputDir, cfg.Notify, previous, summaries)
+
+	return summaries, nil
+}
+
+// loadSummary reads the previous run's summary.json, returning nil without
+// error if none has been written yet.
+func loadSummary(outputDir string) ([]summary, error) {
+	raw, err := os.ReadFile(filepath.Join(outputDir, "summary.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read previous summary.json: %w", err)
+	}
+	var summaries []summary
+	if err := json.Unmarshal(raw, &summaries); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal previous summary.json: %w", err)
+	}
+	return summaries, nil
+}
+
 type tagsOutput struct {
 	HasAdditional bool        `json:"has_additional"`
 	Page          int         `json:"page"`
@@ -99,47 +171,53 @@ type tagOutput struct {
 
 const hyperShiftRepo = "acm-d/rhtap-hypershift-operator"
 
-func publishedTags(ctx context.Context, quayHost, quayToken string, outputDir string) (map[string]time.Time, error) {
-	tagsDir := filepath.Join(outputDir, "tags")
-	if err := os.MkdirAll(tagsDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create dir for tags: %w", err)
-	}
+// publishedTags returns the known published commit SHAs and the time their
+// image tag was last modified. Quay returns tags in last_modified order, so
+// only the pages at the front of the list can ever gain new tags since they
+// were last cached: we always revalidate page 1, and keep revalidating
+// (rather than trusting the disk cache outright) until we hit a page that
+// comes back unmodified, at which point every page behind it is trusted as
+// unchanged too.
+func publishedTags(ctx context.Context, quayHost, quayToken string, cache *httpCache) (map[string]time.Time, error) {
 	tags := map[string]time.Time{}
-	page := 1
 	oldest := time.Now()
+	page := 1
+	revalidate := true
 	for {
+		req, err := tagsRequest(ctx, quayHost, quayToken, page)
+		if err != nil {
+			return nil, err
+		}
+
 		var rawPage []byte
-		pagePath := filepath.Join(tagsDir, fmt.Sprintf("%d.json", page))
-		if _, err := os.Stat(pagePath); err != nil {
-			if !os.IsNotExist(err) {
-				return nil, fmt.Errorf("failed to stat tags file %s: %w", pagePath, err)
-			}
-			// if we don't have the file, we just need to fetch it
-			log.Printf("fetching tags page %d from the API", page)
-			rawPage, err = fetchTags(ctx, quayHost, quayToken, page)
+		if revalidate {
+			var notModified bool
+			rawPage, notModified, err = cache.get(http.DefaultClient, req)
 			if err != nil {
-				return nil, fmt.Errorf("failed to fetch tags page %d from the API: %w", page, err)
+				return nil, fmt.Errorf("failed to fetch tags page %d: %w", page, err)
 			}
-
-			if err := os.WriteFile(pagePath, rawPage, 0644); err != nil {
-				return nil, fmt.Errorf("failed to write tags page %d to the API: %w", page, err)
+			if notModified {
+				log.Printf("tags page %d unmodified, trusting cached pages beyond it", page)
+				revalidate = false
 			}
 		} else {
-			// if we have a file, we can just load it
-			log.Printf("fetching tags page %d from disk", page)
-			var loadErr error
-			rawPage, loadErr = os.ReadFile(pagePath)
-			if loadErr != nil {
-				return nil, fmt.Errorf("failed to read tags file %s: %w", pagePath, loadErr)
+			var ok bool
+			rawPage, ok, err = cache.load(req.URL.String())
+			if err != nil {
+				return nil, fmt.Errorf("failed to load cached tags page %d: %w", page, err)
+			}
+			if !ok {
+				return nil, fmt.Errorf("expected tags page %d to already be cached", page)
 			}
 		}
+
 		var pageData tagsOutput
 		if err := json.Unmarshal(rawPage, &pageData); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal tags file %s: %w", pagePath, err)
+			return nil, fmt.Errorf("failed to unmarshal tags page %d: %w", page, err)
 		}
 
 		if pageData.Page != page {
-			return nil, fmt.Errorf("tags file %s has wrong page, expected %d, got %d", pagePath, page, pageData.Page)
+			return nil, fmt.Errorf("tags page %d response has wrong page, expected %d, got %d", page, page, pageData.Page)
 		}
 
 		for _, tag := range pageData.Tags {
@@ -167,7 +245,7 @@ func publishedTags(ctx context.Context, quayHost, quayToken string, outputDir st
 	return tags, nil
 }
 
-func fetchTags(ctx context.Context, quayHost, quayToken string, page int) ([]byte, error) {
+func tagsRequest(ctx context.Context, quayHost, quayToken string, page int) (*http.Request, error) {
 	uri, err := url.Parse(quayHost + "/api/v1/repository/" + hyperShiftRepo + "/tag")
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse URL: %w", err)
@@ -176,93 +254,40 @@ func fetchTags(ctx context.Context, quayHost, quayToken string, page int) ([]byt
 	query.Add("page", strconv.Itoa(page))
 	uri.RawQuery = query.Encode()
 
-	log.Printf("Fetching url %s", uri.String())
-
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri.String(), nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 	req.Header.Set("Authorization", "Bearer "+quayToken)
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
-	}
-	defer func() {
-		if err := resp.Body.Close(); err != nil {
-			log.Printf("failed to close response body: %v", err)
-		}
-	}()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to fetch tags: %s: %s", resp.Status, string(body))
-	}
-
-	return body, nil
+	return req, nil
 }
 
 type commitInfo struct {
-	sha  string
-	date time.Time
+	sha    string
+	date   time.Time
+	branch string
 }
 
-func mergeCommits(ctx context.Context, firstCommit, remote string) ([]commitInfo, error) {
+func mergeCommits(ctx context.Context, sources []CommitSource, firstCommit string) ([]commitInfo, error) {
 	var commits []commitInfo
 	seen := sets.Set[string]{}
-	for _, branch := range []string{
-		"main",
-		"release-4.18",
-		"release-4.17",
-		"release-4.16",
-		"release-4.15",
-		"release-4.14",
-		"release-4.13",
-		"main-0.1.16-rehearsal-hotfix",
-	} {
-		previous := len(commits)
-		log.Printf("fetching commits for branch %s", branch)
-		args := []string{
-			"log",
-			"--merges",
-			"--pretty=format:%H\u00A0%ad",
-			"--date=iso8601-strict",
-		}
-		if firstCommit != "" {
-			args = append(args, firstCommit+"^1..."+remote+"/"+branch)
-		}
-		cmd := exec.CommandContext(ctx, "git", args...)
-		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
-		cmd.Stdout, cmd.Stderr = stdout, stderr
-		if err := cmd.Run(); err != nil {
-			return nil, fmt.Errorf("failed to run git %s: %s, %s", strings.Join(args, " "), stdout.String(), stderr.String())
+	for _, source := range sources {
+		log.Printf("fetching commits for branch %s", source.Branch())
+		branchCommits, err := source.MergeCommits(ctx, firstCommit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch merge commits for branch %s: %w", source.Branch(), err)
 		}
 
-		for _, line := range strings.Split(stdout.String(), "\n") {
-			line = strings.TrimSpace(line)
-			parts := strings.Split(line, "\u00A0")
-			if len(parts) != 2 {
-				return nil, fmt.Errorf("incorrect parts from git output: %q", line)
-			}
-			commitSha, rawCommittedTime := parts[0], parts[1]
-			if seen.Has(commitSha) {
+		added := 0
+		for _, commit := range branchCommits {
+			if seen.Has(commit.sha) {
 				continue
 			}
-			committedTime, err := time.Parse(time.RFC3339, rawCommittedTime)
-			if err != nil {
-				return nil, fmt.Errorf("invalid time %s: %w", rawCommittedTime, err)
-			}
-			commits = append(commits, commitInfo{
-				sha:  commitSha,
-				date: committedTime,
-			})
-			seen.Insert(commitSha)
+			commits = append(commits, commit)
+			seen.Insert(commit.sha)
+			added++
 		}
-		log.Printf("fetched %d commits for branch %s", len(commits)-previous, branch)
+		log.Printf("fetched %d commits for branch %s", added, source.Branch())
 	}
 	return commits, nil
 }
@@ -275,6 +300,9 @@ type summary struct {
 	Date string `json:"date"`
 	date time.Time
 
+	// Branch is the branch the commit was merged into.
+	Branch string `json:"branch"`
+
 	// Published exposes whether the commit was published into an image tag.
 	Published bool `json:"published"`
 
@@ -283,7 +311,7 @@ type summary struct {
 	publishedTime time.Time
 }
 
-func summarize(tags map[string]time.Time, commits []commitInfo, outputDir string) error {
+func summarize(tags map[string]time.Time, commits []commitInfo, outputDir string) ([]summary, error) {
 	var summaries []summary
 	for _, commit := range commits {
 		date, published := tags[commit.sha]
@@ -291,6 +319,7 @@ func summarize(tags map[string]time.Time, commits []commitInfo, outputDir string
 			Commit:    commit.sha,
 			Date:      commit.date.Format(time.RFC3339),
 			date:      commit.date,
+			Branch:    commit.branch,
 			Published: published,
 
 			publishedTime: date,
@@ -304,14 +333,40 @@ func summarize(tags map[string]time.Time, commits []commitInfo, outputDir string
 		return summaries[i].date.Before(summaries[j].date)
 	})
 
+	if err := writeSummary(summaries, outputDir); err != nil {
+		return nil, err
+	}
+
+	return summaries, nil
+}
+
+// writeSummary atomically replaces summary.json so a concurrent reader (e.g.
+// the HTTP server in watch mode) never observes a partially-written file.
+func writeSummary(summaries []summary, outputDir string) error {
 	raw, err := json.Marshal(summaries)
 	if err != nil {
 		return fmt.Errorf("failed to marshal summary: %w", err)
 	}
 
 	output := filepath.Join(outputDir, "summary.json")
-	if err := os.WriteFile(output, raw, 0644); err != nil {
-		return fmt.Errorf("failed to write summary.json: %w", err)
+	tmp, err := os.CreateTemp(outputDir, ".summary.json.*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file for summary.json: %w", err)
+	}
+	defer func() {
+		if err := os.Remove(tmp.Name()); err != nil && !os.IsNotExist(err) {
+			log.Printf("failed to clean up temp file %s: %v", tmp.Name(), err)
+		}
+	}()
+
+	if _, err := tmp.Write(raw); err != nil {
+		return fmt.Errorf("failed to write temp file for summary.json: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temp file for summary.json: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), output); err != nil {
+		return fmt.Errorf("failed to rename temp file to summary.json: %w", err)
 	}
 
 	log.Printf("wrote %d summaries to %s", len(summaries), output)
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// fakeCommitSource is a CommitSource that returns a fixed list of commits,
+// for exercising mergeCommits' dedup logic without a real backend.
+type fakeCommitSource struct {
+	branch  string
+	commits []commitInfo
+}
+
+func (s *fakeCommitSource) Branch() string { return s.branch }
+
+func (s *fakeCommitSource) MergeCommits(ctx context.Context, firstCommit string) ([]commitInfo, error) {
+	return s.commits, nil
+}
+
+func TestMergeCommitsDedups(t *testing.T) {
+	now := time.Now()
+	sources := []CommitSource{
+		&fakeCommitSource{branch: "main", commits: []commitInfo{
+			{sha: "aaa", date: now, branch: "main"},
+			{sha: "bbb", date: now, branch: "main"},
+		}},
+		// A second source reporting on a different branch, but sharing a
+		// commit (e.g. cherry-picked or backported) with the first: it
+		// should only show up once in the merged result.
+		&fakeCommitSource{branch: "release-4.18", commits: []commitInfo{
+			{sha: "aaa", date: now, branch: "release-4.18"},
+			{sha: "ccc", date: now, branch: "release-4.18"},
+		}},
+	}
+
+	commits, err := mergeCommits(context.Background(), sources, "")
+	if err != nil {
+		t.Fatalf("mergeCommits returned an error: %v", err)
+	}
+	if len(commits) != 3 {
+		t.Fatalf("expected 3 deduped commits, got %d: %+v", len(commits), commits)
+	}
+
+	seen := map[string]bool{}
+	for _, c := range commits {
+		if seen[c.sha] {
+			t.Errorf("commit %s appeared more than once", c.sha)
+		}
+		seen[c.sha] = true
+	}
+	if !seen["aaa"] || !seen["bbb"] || !seen["ccc"] {
+		t.Errorf("expected commits aaa, bbb and ccc, got %+v", commits)
+	}
+}
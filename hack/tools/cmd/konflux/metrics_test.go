@@ -0,0 +1,77 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// histogramSampleCount returns how many observations h has recorded.
+func histogramSampleCount(t *testing.T, h prometheus.Observer) uint64 {
+	t.Helper()
+	var metric dto.Metric
+	if err := h.(prometheus.Metric).Write(&metric); err != nil {
+		t.Fatalf("failed to collect histogram: %v", err)
+	}
+	return metric.GetHistogram().GetSampleCount()
+}
+
+// TestMetricsObserveOnlyOnTransitionToPublished pins the invariant that
+// publishLatency only ever gets one sample per commit, on the poll where it
+// first shows up as published: a long-running --watch process must not
+// re-observe (and inflate _count/_sum for) a commit that was already
+// published on a previous poll.
+func TestMetricsObserveOnlyOnTransitionToPublished(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	m := newMetrics(registry)
+	now := time.Now()
+	date := now.Add(-time.Hour)
+
+	unpublished := []summary{{Commit: "aaa", Branch: "main", date: date, Published: false}}
+	m.observe([]string{"main"}, nil, unpublished, now)
+
+	published := []summary{{Commit: "aaa", Branch: "main", date: date, Published: true, publishedTime: now}}
+	m.observe([]string{"main"}, unpublished, published, now)
+
+	// A second poll where the commit is still published must not add
+	// another sample.
+	m.observe([]string{"main"}, published, published, now)
+
+	count := histogramSampleCount(t, m.publishLatency.WithLabelValues("main"))
+	if count != 1 {
+		t.Fatalf("expected publishLatency to have observed exactly 1 sample, got %v", count)
+	}
+}
+
+func TestMetricsObserveCountsUnpublishedBacklog(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	m := newMetrics(registry)
+	now := time.Now()
+
+	current := []summary{
+		{Commit: "aaa", Branch: "main", date: now.Add(-2 * time.Hour), Published: false},
+		{Commit: "bbb", Branch: "main", date: now.Add(-time.Hour), Published: false},
+	}
+	m.observe([]string{"main"}, nil, current, now)
+
+	if got := testutil.ToFloat64(m.unpublishedCommits.WithLabelValues("main")); got != 2 {
+		t.Fatalf("expected 2 unpublished commits, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.oldestUnpublishedAge.WithLabelValues("main")); got < 2*time.Hour.Seconds()-1 {
+		t.Fatalf("expected oldest unpublished age to reflect the 2h-old commit, got %v", got)
+	}
+
+	// Once everything catches up, the gauges must reset to zero rather than
+	// holding on to the stale backlog.
+	m.observe([]string{"main"}, current, nil, now)
+	if got := testutil.ToFloat64(m.unpublishedCommits.WithLabelValues("main")); got != 0 {
+		t.Fatalf("expected unpublished commits to reset to 0, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.oldestUnpublishedAge.WithLabelValues("main")); got != 0 {
+		t.Fatalf("expected oldest unpublished age to reset to 0, got %v", got)
+	}
+}
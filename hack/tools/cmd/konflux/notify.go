@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// NotifyConfig configures the notify subsystem, which diffs consecutive
+// summarize runs and posts events about commits crossing interesting
+// publication milestones to one or more sinks.
+type NotifyConfig struct {
+	// UnpublishedThreshold is how long a commit can sit unpublished before
+	// sinks start getting reminded about it. Defaults to 4h.
+	UnpublishedThreshold metav1.Duration `json:"unpublishedThreshold,omitempty"`
+	// Sinks receive every event this config produces.
+	Sinks []SinkConfig `json:"sinks,omitempty"`
+	// MutedBranches are excluded from notifications entirely.
+	MutedBranches []string `json:"mutedBranches,omitempty"`
+}
+
+// SinkConfig is somewhere to post notify events.
+type SinkConfig struct {
+	// Type selects the payload shape: "slack" posts a Slack incoming-webhook
+	// message, "webhook" (the default) posts the event as a small JSON object.
+	Type string `json:"type,omitempty"`
+	URL  string `json:"url"`
+}
+
+const defaultUnpublishedThreshold = 4 * time.Hour
+
+// notifyStateFile persists which commits have already fired an
+// unpublished-threshold notification, alongside summary.json, so that
+// notify() doesn't re-notify the same commit on every poll once it crosses
+// the threshold.
+const notifyStateFile = "notify-state.json"
+
+// notifyState is notify's memory of what it has already told sinks about,
+// across process restarts.
+type notifyState struct {
+	// NotifiedUnpublished is the set of commit SHAs that have already
+	// fired an unpublished-threshold event.
+	NotifiedUnpublished map[string]bool `json:"notifiedUnpublished,omitempty"`
+}
+
+// loadNotifyState reads notify's state, returning an empty one if none has
+// been written yet.
+func loadNotifyState(outputDir string) (*notifyState, error) {
+	raw, err := os.ReadFile(filepath.Join(outputDir, notifyStateFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &notifyState{NotifiedUnpublished: map[string]bool{}}, nil
+		}
+		return nil, fmt.Errorf("failed to read notify state: %w", err)
+	}
+	var state notifyState
+	if err := json.Unmarshal(raw, &state); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal notify state: %w", err)
+	}
+	if state.NotifiedUnpublished == nil {
+		state.NotifiedUnpublished = map[string]bool{}
+	}
+	return &state, nil
+}
+
+func writeNotifyState(outputDir string, state *notifyState) error {
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notify state: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(outputDir, notifyStateFile), raw, 0644); err != nil {
+		return fmt.Errorf("failed to write notify state: %w", err)
+	}
+	return nil
+}
+
+// notify diffs previous against current and posts an event to every
+// configured sink for each commit that was newly published, or that has
+// just crossed the configured unpublished threshold. previous may be nil
+// (no prior summary.json yet), in which case newly-published events are
+// suppressed since there's nothing to diff against. Unpublished-threshold
+// events fire at most once per commit: notifyState records which commits
+// already fired one, and is cleared once a commit is published so a commit
+// that is later re-fetched as unpublished (e.g. a reverted tag) can notify
+// again.
+func notify(ctx context.Context, outputDir string, cfg *NotifyConfig, previous, current []summary) {
+	if cfg == nil || len(cfg.Sinks) == 0 {
+		return
+	}
+	threshold := cfg.UnpublishedThreshold.Duration
+	if threshold == 0 {
+		threshold = defaultUnpublishedThreshold
+	}
+	muted := sets.New[string](cfg.MutedBranches...)
+
+	state, err := loadNotifyState(outputDir)
+	if err != nil {
+		log.Printf("failed to load notify state, unpublished notifications may repeat: %v", err)
+		state = &notifyState{NotifiedUnpublished: map[string]bool{}}
+	}
+
+	previouslyUnpublished := sets.Set[string]{}
+	for _, s := range previous {
+		if !s.Published {
+			previouslyUnpublished.Insert(s.Commit)
+		}
+	}
+
+	now := time.Now()
+	var events []string
+	for _, s := range current {
+		if muted.Has(s.Branch) {
+			continue
+		}
+		if s.Published {
+			delete(state.NotifiedUnpublished, s.Commit)
+			if previous != nil && previouslyUnpublished.Has(s.Commit) {
+				latency := s.publishedTime.Sub(s.date)
+				events = append(events, fmt.Sprintf("commit %s on branch %s was published to an image tag after %s", s.Commit, s.Branch, latency.Round(time.Minute)))
+			}
+			continue
+		}
+		if age := now.Sub(s.date); age > threshold && !state.NotifiedUnpublished[s.Commit] {
+			events = append(events, fmt.Sprintf("commit %s on branch %s has been unpublished for %s", s.Commit, s.Branch, age.Round(time.Minute)))
+			state.NotifiedUnpublished[s.Commit] = true
+		}
+	}
+
+	for _, event := range events {
+		for _, sink := range cfg.Sinks {
+			if err := postEvent(ctx, sink, event); err != nil {
+				log.Printf("failed to notify sink %s: %v", sink.URL, err)
+			}
+		}
+	}
+
+	if err := writeNotifyState(outputDir, state); err != nil {
+		log.Printf("failed to persist notify state: %v", err)
+	}
+}
+
+func postEvent(ctx context.Context, sink SinkConfig, message string) error {
+	var payload any
+	switch sink.Type {
+	case "slack":
+		payload = struct {
+			Text string `json:"text"`
+		}{Text: message}
+	case "", "webhook":
+		payload = struct {
+			Message string `json:"message"`
+		}{Message: message}
+	default:
+		return fmt.Errorf("unknown sink type %q", sink.Type)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sink.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Printf("failed to close response body: %v", err)
+		}
+	}()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("sink responded with %s", resp.Status)
+	}
+	return nil
+}
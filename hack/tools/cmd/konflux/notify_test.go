@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TestNotifyUnpublishedThresholdFiresOnce pins the invariant that a commit
+// sitting past the unpublished threshold only ever fires one event, tracked
+// across calls via notify-state.json: a long-running --watch process must
+// not re-notify on every poll while the commit remains unpublished.
+func TestNotifyUnpublishedThresholdFiresOnce(t *testing.T) {
+	events := 0
+	sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		events++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer sink.Close()
+
+	cfg := &NotifyConfig{
+		UnpublishedThreshold: metav1.Duration{Duration: time.Hour},
+		Sinks:                []SinkConfig{{URL: sink.URL}},
+	}
+	outputDir := t.TempDir()
+	stale := []summary{{Commit: "aaa", Branch: "main", date: time.Now().Add(-2 * time.Hour), Published: false}}
+
+	notify(context.Background(), outputDir, cfg, nil, stale)
+	if events != 1 {
+		t.Fatalf("expected exactly 1 event on the first poll past the threshold, got %d", events)
+	}
+
+	// A second poll where the commit is still unpublished must not fire
+	// another event.
+	notify(context.Background(), outputDir, cfg, stale, stale)
+	if events != 1 {
+		t.Fatalf("expected no additional event on a repeat poll, still at 1, got %d", events)
+	}
+}
+
+func TestNotifyUnpublishedThresholdFiresAgainAfterRepublishing(t *testing.T) {
+	events := 0
+	sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		events++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer sink.Close()
+
+	cfg := &NotifyConfig{
+		UnpublishedThreshold: metav1.Duration{Duration: time.Hour},
+		Sinks:                []SinkConfig{{URL: sink.URL}},
+	}
+	outputDir := t.TempDir()
+	stale := []summary{{Commit: "aaa", Branch: "main", date: time.Now().Add(-2 * time.Hour), Published: false}}
+	published := []summary{{Commit: "aaa", Branch: "main", date: stale[0].date, Published: true, publishedTime: time.Now()}}
+
+	notify(context.Background(), outputDir, cfg, nil, stale)
+	if events != 1 {
+		t.Fatalf("expected exactly 1 event for the initial unpublished-threshold crossing, got %d", events)
+	}
+
+	// Once the commit publishes, its notified-state is cleared and the
+	// transition itself fires a "was published" event.
+	notify(context.Background(), outputDir, cfg, stale, published)
+	if events != 2 {
+		t.Fatalf("expected a publish event on the publishing poll, got %d total", events)
+	}
+
+	// If it's later seen unpublished again (e.g. a reverted tag), it can
+	// cross the threshold and notify once more.
+	notify(context.Background(), outputDir, cfg, published, stale)
+	if events != 3 {
+		t.Fatalf("expected a third event after the commit was republished as unpublished, got %d", events)
+	}
+}
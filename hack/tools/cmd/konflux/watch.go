@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// watch runs runOnce on --poll-interval, serving the latest summary (and, if
+// --http-addr is set, a small HTTP API) until ctx is cancelled.
+func watch(ctx context.Context, opts *options, cfg *Config, sources []CommitSource, tagsCache *httpCache) error {
+	state := &servedState{}
+	registry := prometheus.NewRegistry()
+	m := newMetrics(registry)
+
+	// Prime state from the last run's summary.json, if any, so a restart of
+	// a long-running --watch process diffs against what was actually
+	// published last time instead of treating every already-published
+	// commit on the first poll as a fresh publish.
+	primed, err := loadSummary(opts.outputDir)
+	if err != nil {
+		return err
+	}
+	state.set(primed)
+
+	knownBranches := make([]string, 0, len(sources))
+	for _, source := range sources {
+		knownBranches = append(knownBranches, source.Branch())
+	}
+
+	if opts.httpAddr != "" {
+		server := &http.Server{Addr: opts.httpAddr, Handler: state.handler(registry)}
+		go func() {
+			log.Printf("serving summary.json, healthz and metrics on %s", opts.httpAddr)
+			if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Printf("http server failed: %v", err)
+			}
+		}()
+		defer func() {
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := server.Shutdown(shutdownCtx); err != nil {
+				log.Printf("failed to shut down http server: %v", err)
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(opts.pollInterval)
+	defer ticker.Stop()
+	for {
+		previous := state.get()
+		summaries, err := runOnce(ctx, opts, cfg, sources, tagsCache)
+		if err != nil {
+			log.Printf("failed to run analysis pass: %v", err)
+		} else {
+			state.set(summaries)
+			m.observe(knownBranches, previous, summaries, time.Now())
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// servedState holds the most recent summary so the HTTP handlers can answer
+// without touching disk, guarded against concurrent updates from the poll
+// loop.
+type servedState struct {
+	mu        sync.RWMutex
+	summaries []summary
+}
+
+func (s *servedState) set(summaries []summary) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.summaries = summaries
+}
+
+func (s *servedState) get() []summary {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.summaries
+}
+
+func (s *servedState) handler(registry *prometheus.Registry) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/summary.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(s.get()); err != nil {
+			log.Printf("failed to write summary.json response: %v", err)
+		}
+	})
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		if _, err := fmt.Fprintln(w, "ok"); err != nil {
+			log.Printf("failed to write healthz response: %v", err)
+		}
+	})
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	return mux
+}
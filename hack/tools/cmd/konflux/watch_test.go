@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// freeAddr reserves and immediately releases a loopback port, for handing to
+// a server started later by the code under test.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to reserve a port: %v", err)
+	}
+	addr := ln.Addr().String()
+	if err := ln.Close(); err != nil {
+		t.Fatalf("failed to release reserved port: %v", err)
+	}
+	return addr
+}
+
+// TestWatchPrimesStateFromDisk pins the invariant that restarting a
+// --watch process, with a summary.json already on disk from before the
+// restart, doesn't treat every already-published commit as a fresh publish:
+// watch() must prime its served state (and therefore what it diffs against
+// in m.observe) from that file before the first poll, not start from nil.
+func TestWatchPrimesStateFromDisk(t *testing.T) {
+	const sha = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	mergeDate := time.Now().Add(-24 * time.Hour).UTC()
+	publishedDate := time.Now().Add(-23 * time.Hour).UTC()
+
+	quay := newFakeQuayServer(t, sha, publishedDate)
+	defer quay.Close()
+
+	outputDir := t.TempDir()
+	seedSummary(t, outputDir, []summary{
+		{
+			Commit:        sha,
+			Date:          mergeDate.Format(time.RFC3339),
+			Branch:        "main",
+			Published:     true,
+			PublishedTime: publishedDate.Format(time.RFC3339),
+		},
+	})
+
+	opts := defaultOptions()
+	opts.quayHost = quay.URL
+	opts.quayToken = "test-token"
+	opts.outputDir = outputDir
+	opts.pollInterval = time.Hour
+	opts.httpAddr = freeAddr(t)
+
+	sources := []CommitSource{&fakeCommitSource{branch: "main", commits: []commitInfo{
+		{sha: sha, date: mergeDate, branch: "main"},
+	}}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- watch(ctx, opts, &Config{}, sources, newHTTPCache(t.TempDir())) }()
+
+	waitForHealthy(t, opts.httpAddr)
+	// The server answers before the first poll finishes, so wait until
+	// m.observe has run (it always sets the backlog gauge for every known
+	// branch) before checking what it did to the latency histogram.
+	var metricsBody string
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		metricsBody = scrape(t, opts.httpAddr, "/metrics")
+		if strings.Contains(metricsBody, "hypershift_unpublished_commits") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("the first poll never updated metrics, got:\n%s", metricsBody)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("watch returned an error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("watch did not return after ctx was cancelled")
+	}
+
+	if strings.Contains(metricsBody, "hypershift_commit_publish_latency_seconds_sum") {
+		t.Fatalf("expected no publish-latency sample for a commit already published before the restart, got metrics:\n%s", metricsBody)
+	}
+}
+
+func newFakeQuayServer(t *testing.T, sha string, lastModified time.Time) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := tagsOutput{
+			Page: 1,
+			Tags: []tagOutput{{Name: sha, LastModified: lastModified.Format(time.RFC1123Z)}},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(page)
+	}))
+}
+
+func seedSummary(t *testing.T, outputDir string, summaries []summary) {
+	t.Helper()
+	raw, err := json.Marshal(summaries)
+	if err != nil {
+		t.Fatalf("failed to marshal seed summary: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(outputDir, "summary.json"), raw, 0644); err != nil {
+		t.Fatalf("failed to write seed summary: %v", err)
+	}
+}
+
+func waitForHealthy(t *testing.T, addr string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		resp, err := http.Get("http://" + addr + "/healthz")
+		if err == nil {
+			_ = resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("watch's http server never became healthy")
+}
+
+func scrape(t *testing.T, addr, path string) string {
+	t.Helper()
+	resp, err := http.Get("http://" + addr + path)
+	if err != nil {
+		t.Fatalf("failed to GET %s: %v", path, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read %s response: %v", path, err)
+	}
+	return string(body)
+}